@@ -0,0 +1,80 @@
+// Copyright 2013 Bellua Ltd. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keyvalue
+
+import (
+  "bytes"
+  "io"
+  "sort"
+
+  "golang.org/x/text/unicode/norm"
+)
+
+// CanonicalString returns the Key-Value Form Encoded message with
+// keys sorted lexicographically, giving deterministic byte output
+// suitable for hashing, diffing, or caching a Form.
+func (f Form) CanonicalString() string {
+  var buf bytes.Buffer
+  f.WriteCanonical(&buf)
+  return buf.String()
+}
+
+// WriteCanonical writes f's canonical (key-sorted) Key-Value Form
+// Encoding to w, streaming one key/value pair at a time.
+func (f Form) WriteCanonical(w io.Writer) error {
+  keys := sortedKeys(f)
+  for _, k := range keys {
+    if _, err := io.WriteString(w, k); err != nil {
+      return err
+    }
+    if _, err := io.WriteString(w, string(colon)); err != nil {
+      return err
+    }
+    if _, err := io.WriteString(w, f[k]); err != nil {
+      return err
+    }
+    if _, err := io.WriteString(w, newline); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func sortedKeys(f Form) []string {
+  keys := make([]string, 0, len(f))
+  for k := range f {
+    keys = append(keys, k)
+  }
+  sort.Strings(keys)
+  return keys
+}
+
+// normalizeNFC returns a copy of f with every key and value put into
+// Unicode Normalization Form C, as required to compare two Forms that
+// may use different, canonically equivalent encodings of the same
+// text.
+func normalizeNFC(f Form) Form {
+  out := make(Form, len(f))
+  for k, v := range f {
+    out[norm.NFC.String(k)] = norm.NFC.String(v)
+  }
+  return out
+}
+
+// Equal reports whether f and other encode the same Key-Value Form
+// message, comparing keys and values after Unicode NFC normalization
+// and independently of map iteration order.
+func (f Form) Equal(other Form) bool {
+  a, b := normalizeNFC(f), normalizeNFC(other)
+  if len(a) != len(b) {
+    return false
+  }
+  for k, v := range a {
+    if bv, ok := b[k]; !ok || bv != v {
+      return false
+    }
+  }
+  return true
+}
@@ -0,0 +1,65 @@
+package keyvalue
+
+import (
+  "bytes"
+  "testing"
+)
+
+type CanonicalStringTest struct {
+  f   Form
+  out string
+}
+
+var CanonicalStringTests = []CanonicalStringTest{
+  {Form{"foo": "bar"}, "foo:bar\n"},
+  {Form{"santa": "banta", "foo": "bar"}, "foo:bar\nsanta:banta\n"},
+  {nil, ""},
+  {Form{}, ""},
+}
+
+func TestFormCanonicalString(t *testing.T) {
+  for _, tt := range CanonicalStringTests {
+    if s := tt.f.CanonicalString(); s != tt.out {
+      t.Errorf("%+v.CanonicalString() = %s, want %s", tt.f, s, tt.out)
+    }
+  }
+}
+
+func TestFormWriteCanonical(t *testing.T) {
+  f := Form{"santa": "banta", "foo": "bar"}
+  var buf bytes.Buffer
+  if err := f.WriteCanonical(&buf); err != nil {
+    t.Fatalf("WriteCanonical returned error: %v", err)
+  }
+  if got, want := buf.String(), "foo:bar\nsanta:banta\n"; got != want {
+    t.Errorf("WriteCanonical wrote %s, want %s", got, want)
+  }
+}
+
+func TestFormWrite(t *testing.T) {
+  f := Form{"foo": "bar"}
+  var buf bytes.Buffer
+  if err := f.Write(&buf); err != nil {
+    t.Fatalf("Write returned error: %v", err)
+  }
+  if got, want := buf.String(), "foo:bar\n"; got != want {
+    t.Errorf("Write wrote %s, want %s", got, want)
+  }
+}
+
+func TestFormEqual(t *testing.T) {
+  a := Form{"foo": "bar", "santa": "banta"}
+  b := Form{"santa": "banta", "foo": "bar"}
+  if !a.Equal(b) {
+    t.Errorf("%+v.Equal(%+v) = false, want true", a, b)
+  }
+
+  c := Form{"foo": "baz"}
+  if a.Equal(c) {
+    t.Errorf("%+v.Equal(%+v) = true, want false", a, c)
+  }
+
+  if !(Form(nil)).Equal(Form{}) {
+    t.Errorf("nil Form.Equal(empty Form) = false, want true")
+  }
+}
@@ -0,0 +1,134 @@
+// Copyright 2013 Bellua Ltd. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keyvalue
+
+import (
+  "bufio"
+  "bytes"
+  "fmt"
+  "io"
+  "strings"
+)
+
+// A Decoder reads and decodes a Key-Value Form Encoded message from an
+// input stream.
+type Decoder struct {
+  r       *bufio.Reader
+  lineNum int
+  err     error
+}
+
+// NewDecoder returns a new Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+  return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Token returns the next key/value pair in the message.
+//
+// Token enforces the same rules as Form.Validate: the message MUST be
+// valid UTF-8, a key MUST NOT contain a colon or a newline, and no
+// whitespace may appear immediately before the colon or immediately
+// after it. Each line MUST be terminated by a single newline and no
+// content may follow the final newline. Token returns io.EOF once the
+// message has been fully consumed.
+func (d *Decoder) Token() (key, value string, err error) {
+  if d.err != nil {
+    return "", "", d.err
+  }
+
+  line, rerr := d.r.ReadString('\n')
+  if rerr != nil {
+    if rerr != io.EOF {
+      d.err = rerr
+      return "", "", d.err
+    }
+    if len(line) > 0 {
+      d.err = fmt.Errorf("keyvalue: line %d: unterminated line at end of message", d.lineNum+1)
+      return "", "", d.err
+    }
+    d.err = io.EOF
+    return "", "", io.EOF
+  }
+  d.lineNum++
+  line = strings.TrimSuffix(line, newline)
+
+  idx := strings.IndexRune(line, colon)
+  if idx < 0 {
+    d.err = fmt.Errorf("keyvalue: line %d: missing colon", d.lineNum)
+    return "", "", d.err
+  }
+  key, value = line[:idx], line[idx+1:]
+
+  if verr := validateKV(key, value); verr != nil {
+    d.err = fmt.Errorf("keyvalue: line %d: %v", d.lineNum, verr)
+    return "", "", d.err
+  }
+  return key, value, nil
+}
+
+// Decode reads a full Key-Value Form Encoded message and returns the
+// resulting Form. Decode rejects messages that contain the same key
+// more than once.
+func (d *Decoder) Decode() (Form, error) {
+  f := make(Form)
+  for {
+    k, v, err := d.Token()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return nil, err
+    }
+    if _, ok := f[k]; ok {
+      return nil, fmt.Errorf("keyvalue: line %d: duplicate key %q", d.lineNum, k)
+    }
+    f[k] = v
+  }
+  return f, nil
+}
+
+// DecodeSignedForm reads a Key-Value Form Encoded message whose keys
+// carry the wire "openid." prefix (as in an OP direct response), using
+// the message's "openid.signed" field as the list of signed keys. The
+// returned SignedForm's Form has that prefix stripped, so it uses the
+// same unprefixed key convention as SignedString, Sign and Verify. It
+// returns an error if "openid.signed" is absent, or if any key it
+// names is missing from the decoded Form.
+func (d *Decoder) DecodeSignedForm() (*SignedForm, error) {
+  f, err := d.Decode()
+  if err != nil {
+    return nil, err
+  }
+
+  signed := f.Get(prefix + "signed")
+  if len(signed) == 0 {
+    return nil, ErrMissingFields
+  }
+
+  unprefixed := make(Form, len(f))
+  for k, v := range f {
+    unprefixed[strings.TrimPrefix(k, prefix)] = v
+  }
+
+  keys := strings.Split(signed, string(comma))
+  for _, k := range keys {
+    if len(unprefixed.Get(k)) == 0 {
+      return nil, fmt.Errorf("keyvalue: signed field %q missing from form", k)
+    }
+  }
+  return &SignedForm{Form: unprefixed, Fields: keys}, nil
+}
+
+// Unmarshal parses a Key-Value Form Encoded message and returns the
+// resulting Form.
+func Unmarshal(data []byte) (Form, error) {
+  return NewDecoder(bytes.NewReader(data)).Decode()
+}
+
+// DecodeSignedForm parses a Key-Value Form Encoded message read from r
+// and reconstructs a SignedForm from it. See Decoder.DecodeSignedForm.
+func DecodeSignedForm(r io.Reader) (*SignedForm, error) {
+  return NewDecoder(r).DecodeSignedForm()
+}
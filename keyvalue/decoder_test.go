@@ -0,0 +1,91 @@
+package keyvalue
+
+import (
+  "strings"
+  "testing"
+)
+
+type DecodeTest struct {
+  in         string
+  out        Form
+  expectedOk bool
+}
+
+var DecodeTests = []DecodeTest{
+  // Valid message
+  {"foo:bar\n", Form{"foo": "bar"}, true},
+  {"foo:bar\nsanta:banta\n", Form{"foo": "bar", "santa": "banta"}, true},
+  // Empty message
+  {"", Form{}, true},
+  // Missing colon
+  {"foobar\n", nil, false},
+  // Duplicate key
+  {"foo:bar\nfoo:baz\n", nil, false},
+  // Missing final newline
+  {"foo:bar", nil, false},
+  // Whitespace around key
+  {" foo:bar\n", nil, false},
+  // Whitespace around value
+  {"foo: bar\n", nil, false},
+}
+
+func TestDecode(t *testing.T) {
+  for _, tt := range DecodeTests {
+    f, err := NewDecoder(strings.NewReader(tt.in)).Decode()
+    ok := err == nil
+    if ok != tt.expectedOk {
+      t.Errorf("Decode(%q) ok = %v (%v), want %v", tt.in, ok, err, tt.expectedOk)
+      continue
+    }
+    if !ok {
+      continue
+    }
+    if len(f) != len(tt.out) {
+      t.Errorf("Decode(%q) = %v, want %v", tt.in, f, tt.out)
+      continue
+    }
+    for k, v := range tt.out {
+      if f[k] != v {
+        t.Errorf("Decode(%q) = %v, want %v", tt.in, f, tt.out)
+      }
+    }
+  }
+}
+
+func TestUnmarshal(t *testing.T) {
+  f, err := Unmarshal([]byte("foo:bar\n"))
+  if err != nil {
+    t.Fatalf("Unmarshal returned error: %v", err)
+  }
+  if f.Get("foo") != "bar" {
+    t.Errorf("Unmarshal got %v, want foo=bar", f)
+  }
+}
+
+func TestDecodeSignedForm(t *testing.T) {
+  msg := "openid.signed:foo,santa\nopenid.foo:bar\nopenid.santa:banta\n"
+  sf, err := DecodeSignedForm(strings.NewReader(msg))
+  if err != nil {
+    t.Fatalf("DecodeSignedForm returned error: %v", err)
+  }
+  if got, want := sf.SignedFields(), "foo,santa"; got != want {
+    t.Errorf("SignedFields() = %s, want %s", got, want)
+  }
+  if sf.Form.Get("foo") != "bar" {
+    t.Errorf("decoded Form missing unprefixed foo, got %v", sf.Form)
+  }
+  if sf.Form.Get("openid.foo") != "" {
+    t.Errorf("decoded Form kept openid.-prefixed key, got %v", sf.Form)
+  }
+
+  // Missing signed field list.
+  if _, err := DecodeSignedForm(strings.NewReader("openid.foo:bar\n")); err != ErrMissingFields {
+    t.Errorf("DecodeSignedForm with no openid.signed: err = %v, want %v", err, ErrMissingFields)
+  }
+
+  // Listed key absent from the form.
+  missing := "openid.signed:foo,santa\nopenid.foo:bar\n"
+  if _, err := DecodeSignedForm(strings.NewReader(missing)); err == nil {
+    t.Errorf("DecodeSignedForm with missing signed field: want error, got nil")
+  }
+}
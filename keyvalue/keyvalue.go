@@ -78,47 +78,63 @@ func (f Form) Del(key string) {
 // The message MUST be encoded in UTF-8 to produce a byte string.
 func (f Form) Validate() error {
   for k, v := range f {
-    // Empty value
-    if len(v) == 0 {
-      return fmt.Errorf("Empty value for key \"%s\"", k)
+    if err := validateKV(k, v); err != nil {
+      return err
     }
+  }
+  return nil
+}
 
-    // Verify that the message line consists entirely of valid
-    // UTF-8-encoded runes
-    if !utf8.ValidString(k) {
-      return fmt.Errorf("key must consists of valid UTF-8-encoded runes.")
-    }
-    if !utf8.ValidString(v) {
-      return fmt.Errorf("value must consists of valid UTF-8-encoded runes.")
-    }
+// validateKV applies the per-key/value rules of Form.Validate to a
+// single key/value pair. It is shared by Form.Validate, which checks
+// an already-built Form, and Decoder, which checks each pair as it is
+// read off the wire.
+func validateKV(k, v string) error {
+  // Empty value
+  if len(v) == 0 {
+    return fmt.Errorf("Empty value for key \"%s\"", k)
+  }
 
-    // key or value MUST NOT contain a newline and a key also MUST NOT contain a
-    // colon.
-    if strings.ContainsRune(k, '\n') || strings.ContainsRune(k, ':') {
-      return fmt.Errorf("key contains a new line or colon \"%s\"", k)
-    }
+  // Verify that the message line consists entirely of valid
+  // UTF-8-encoded runes
+  if !utf8.ValidString(k) {
+    return fmt.Errorf("key must consists of valid UTF-8-encoded runes.")
+  }
+  if !utf8.ValidString(v) {
+    return fmt.Errorf("value must consists of valid UTF-8-encoded runes.")
+  }
 
-    // Before the colon
-    if r, _ := utf8.DecodeLastRuneInString(k); unicode.IsSpace(r) {
-      return fmt.Errorf("whitespace at end of key \"%s\"", k)
-    }
-    // After the newline
-    if r, _ := utf8.DecodeRuneInString(k); unicode.IsSpace(r) {
-      return fmt.Errorf("whitespace at beginning of key \"%s\"", k)
-    }
-    // After the colon
-    if r, _ := utf8.DecodeLastRuneInString(v); unicode.IsSpace(r) {
-      return fmt.Errorf("whitespace at beginning of value \"%s\"", v)
-    }
-    // Before the newline
-    if r, _ := utf8.DecodeRuneInString(v); unicode.IsSpace(r) {
-      return fmt.Errorf("whitespace at end of value \"%s\"", v)
-    }
+  // key or value MUST NOT contain a newline and a key also MUST NOT contain a
+  // colon.
+  if strings.ContainsRune(k, '\n') || strings.ContainsRune(k, ':') {
+    return fmt.Errorf("key contains a new line or colon \"%s\"", k)
+  }
+
+  // Before the colon
+  if r, _ := utf8.DecodeLastRuneInString(k); unicode.IsSpace(r) {
+    return fmt.Errorf("whitespace at end of key \"%s\"", k)
+  }
+  // After the newline
+  if r, _ := utf8.DecodeRuneInString(k); unicode.IsSpace(r) {
+    return fmt.Errorf("whitespace at beginning of key \"%s\"", k)
+  }
+  // After the colon
+  if r, _ := utf8.DecodeRuneInString(v); unicode.IsSpace(r) {
+    return fmt.Errorf("whitespace at beginning of value \"%s\"", v)
+  }
+  // Before the newline
+  if r, _ := utf8.DecodeLastRuneInString(v); unicode.IsSpace(r) {
+    return fmt.Errorf("whitespace at end of value \"%s\"", v)
   }
   return nil
 }
 
 // String returns the Key-Value Form Encoded message.
+//
+// String iterates the underlying map, so the order of lines in the
+// returned message is non-deterministic across calls. Callers that
+// need to hash, diff, cache or otherwise reproduce a KV blob should
+// use CanonicalString instead.
 func (f Form) String() string {
   if f == nil || len(f) == 0 {
     return ""
@@ -142,9 +158,25 @@ func (f Form) String() string {
 
   return buf.String()
 }
+// Write streams f's Key-Value Form Encoding to w, one key/value pair
+// at a time, without materializing the full message in memory. As
+// with String, the order of lines is non-deterministic.
 func (f Form) Write(w io.Writer) error {
-  _, err := io.WriteString(w, f.String())
-  return err
+  for k, v := range f {
+    if _, err := io.WriteString(w, k); err != nil {
+      return err
+    }
+    if _, err := io.WriteString(w, string(colon)); err != nil {
+      return err
+    }
+    if _, err := io.WriteString(w, v); err != nil {
+      return err
+    }
+    if _, err := io.WriteString(w, newline); err != nil {
+      return err
+    }
+  }
+  return nil
 }
 
 // Signed message in key-value form.
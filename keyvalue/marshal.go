@@ -0,0 +1,274 @@
+// Copyright 2013 Bellua Ltd. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keyvalue
+
+import (
+  "fmt"
+  "reflect"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// timeType is used to tell time.Time apart from an arbitrary nested
+// struct when walking fields with reflection.
+var timeType = reflect.TypeOf(time.Time{})
+
+// Marshal returns the Form encoding of v, driven by "keyvalue" struct
+// tags of the form `keyvalue:"name,omitempty,sign"`.
+//
+// Supported field kinds are string, the integer/float/bool kinds
+// (encoded textually), time.Time (RFC3339) and []string (joined with
+// commas, per OpenID convention). A nested struct is flattened with a
+// dotted prefix: an embedded struct tagged `keyvalue:"ns"` produces
+// keys of the form "ns.foo".
+//
+// The "omitempty" option skips the field when it holds its zero
+// value. The "sign" option marks the field as wanting to be signed;
+// use MarshalSigned to collect those fields into a SignedForm.
+func Marshal(v interface{}) (Form, error) {
+  f := make(Form)
+  if err := f.Encode(v); err != nil {
+    return nil, err
+  }
+  return f, nil
+}
+
+// MarshalSigned is like Marshal but also collects the fields tagged
+// "sign", in declaration order, into a ready-to-sign SignedForm.
+func MarshalSigned(v interface{}) (*SignedForm, error) {
+  f := make(Form)
+  signed, err := encodeValue(reflect.ValueOf(v), "", f)
+  if err != nil {
+    return nil, err
+  }
+  return &SignedForm{Form: f, Fields: signed}, nil
+}
+
+// Encode walks v's "keyvalue"-tagged fields and sets the resulting
+// keys and values on f.
+func (f Form) Encode(v interface{}) error {
+  _, err := encodeValue(reflect.ValueOf(v), "", f)
+  return err
+}
+
+// Decode populates v's "keyvalue"-tagged fields from f. v must be a
+// pointer to a struct.
+//
+// There is no package-level Unmarshal(data []byte, v interface{})
+// counterpart to Marshal: that name is already taken by the
+// byte-decoding Unmarshal([]byte) (Form, error) in decoder.go. To
+// decode a message straight into a struct, combine the two:
+//
+//   f, err := keyvalue.Unmarshal(data)
+//   if err != nil { ... }
+//   err = f.Decode(&v)
+func (f Form) Decode(v interface{}) error {
+  rv := reflect.ValueOf(v)
+  if rv.Kind() != reflect.Ptr || rv.IsNil() {
+    return fmt.Errorf("keyvalue: Decode requires a non-nil pointer to a struct, got %T", v)
+  }
+  return decodeStruct(f, rv.Elem(), "")
+}
+
+func encodeValue(rv reflect.Value, keyPrefix string, f Form) ([]string, error) {
+  for rv.Kind() == reflect.Ptr {
+    if rv.IsNil() {
+      return nil, nil
+    }
+    rv = rv.Elem()
+  }
+  if rv.Kind() != reflect.Struct || rv.Type() == timeType {
+    return nil, fmt.Errorf("keyvalue: Encode requires a struct, got %s", rv.Kind())
+  }
+
+  var signed []string
+  rt := rv.Type()
+  for i := 0; i < rt.NumField(); i++ {
+    field := rt.Field(i)
+    if field.PkgPath != "" {
+      continue
+    }
+    name, omitempty, sign, skip := parseTag(field)
+    if skip {
+      continue
+    }
+
+    key := name
+    if keyPrefix != "" {
+      key = keyPrefix + "." + name
+    }
+
+    fv := rv.Field(i)
+    if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+      nested, err := encodeValue(fv, key, f)
+      if err != nil {
+        return nil, err
+      }
+      signed = append(signed, nested...)
+      continue
+    }
+
+    s, isZero, err := encodeScalar(fv)
+    if err != nil {
+      return nil, fmt.Errorf("keyvalue: field %s: %v", field.Name, err)
+    }
+    if omitempty && isZero {
+      continue
+    }
+    f.Set(key, s)
+    if sign {
+      signed = append(signed, key)
+    }
+  }
+  return signed, nil
+}
+
+func encodeScalar(fv reflect.Value) (s string, isZero bool, err error) {
+  switch {
+  case fv.Type() == timeType:
+    t := fv.Interface().(time.Time)
+    return t.Format(time.RFC3339), t.IsZero(), nil
+  case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+    elems := make([]string, fv.Len())
+    for i := range elems {
+      elems[i] = fv.Index(i).String()
+    }
+    return strings.Join(elems, string(comma)), fv.Len() == 0, nil
+  }
+
+  switch fv.Kind() {
+  case reflect.String:
+    return fv.String(), fv.Len() == 0, nil
+  case reflect.Bool:
+    return strconv.FormatBool(fv.Bool()), !fv.Bool(), nil
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    return strconv.FormatInt(fv.Int(), 10), fv.Int() == 0, nil
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+    return strconv.FormatUint(fv.Uint(), 10), fv.Uint() == 0, nil
+  case reflect.Float32:
+    return strconv.FormatFloat(fv.Float(), 'g', -1, 32), fv.Float() == 0, nil
+  case reflect.Float64:
+    return strconv.FormatFloat(fv.Float(), 'g', -1, 64), fv.Float() == 0, nil
+  }
+  return "", false, fmt.Errorf("unsupported kind %s", fv.Kind())
+}
+
+func decodeStruct(f Form, rv reflect.Value, keyPrefix string) error {
+  rt := rv.Type()
+  for i := 0; i < rt.NumField(); i++ {
+    field := rt.Field(i)
+    if field.PkgPath != "" {
+      continue
+    }
+    name, _, _, skip := parseTag(field)
+    if skip {
+      continue
+    }
+
+    key := name
+    if keyPrefix != "" {
+      key = keyPrefix + "." + name
+    }
+
+    fv := rv.Field(i)
+    if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+      if err := decodeStruct(f, fv, key); err != nil {
+        return err
+      }
+      continue
+    }
+
+    value := f.Get(key)
+    if len(value) == 0 {
+      continue
+    }
+    if err := decodeScalar(fv, value); err != nil {
+      return fmt.Errorf("keyvalue: field %s: %v", field.Name, err)
+    }
+  }
+  return nil
+}
+
+func decodeScalar(fv reflect.Value, value string) error {
+  switch {
+  case fv.Type() == timeType:
+    t, err := time.Parse(time.RFC3339, value)
+    if err != nil {
+      return err
+    }
+    fv.Set(reflect.ValueOf(t))
+    return nil
+  case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+    parts := strings.Split(value, string(comma))
+    fv.Set(reflect.MakeSlice(fv.Type(), len(parts), len(parts)))
+    for i, p := range parts {
+      fv.Index(i).SetString(p)
+    }
+    return nil
+  }
+
+  switch fv.Kind() {
+  case reflect.String:
+    fv.SetString(value)
+    return nil
+  case reflect.Bool:
+    b, err := strconv.ParseBool(value)
+    if err != nil {
+      return err
+    }
+    fv.SetBool(b)
+    return nil
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    n, err := strconv.ParseInt(value, 10, 64)
+    if err != nil {
+      return err
+    }
+    fv.SetInt(n)
+    return nil
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+    n, err := strconv.ParseUint(value, 10, 64)
+    if err != nil {
+      return err
+    }
+    fv.SetUint(n)
+    return nil
+  case reflect.Float32, reflect.Float64:
+    n, err := strconv.ParseFloat(value, 64)
+    if err != nil {
+      return err
+    }
+    fv.SetFloat(n)
+    return nil
+  }
+  return fmt.Errorf("unsupported kind %s", fv.Kind())
+}
+
+// parseTag reads field's "keyvalue" struct tag, returning the key
+// name and options. skip is true if the field should be ignored
+// (tagged "-" or untagged).
+func parseTag(field reflect.StructField) (name string, omitempty, sign, skip bool) {
+  tag := field.Tag.Get("keyvalue")
+  if tag == "" {
+    return "", false, false, true
+  }
+  parts := strings.Split(tag, string(comma))
+  name = parts[0]
+  if name == "-" {
+    return "", false, false, true
+  }
+  if name == "" {
+    name = field.Name
+  }
+  for _, opt := range parts[1:] {
+    switch opt {
+    case "omitempty":
+      omitempty = true
+    case "sign":
+      sign = true
+    }
+  }
+  return name, omitempty, sign, false
+}
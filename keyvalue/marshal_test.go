@@ -0,0 +1,85 @@
+package keyvalue
+
+import (
+  "reflect"
+  "testing"
+  "time"
+)
+
+type ns struct {
+  Foo string `keyvalue:"foo"`
+}
+
+type marshalTestStruct struct {
+  Mode      string    `keyvalue:"mode"`
+  AssocType string    `keyvalue:"assoc_type,omitempty"`
+  Sig       string    `keyvalue:"sig,sign"`
+  Realm     string    `keyvalue:"realm,omitempty"`
+  Tags      []string  `keyvalue:"tags"`
+  Issued    time.Time `keyvalue:"issued"`
+  Ignored   string
+  NS        ns `keyvalue:"ns"`
+}
+
+func TestMarshal(t *testing.T) {
+  issued := time.Date(2013, time.January, 2, 3, 4, 5, 0, time.UTC)
+  v := marshalTestStruct{
+    Mode:   "id_res",
+    Sig:    "abc123",
+    Tags:   []string{"a", "b"},
+    Issued: issued,
+    NS:     ns{Foo: "bar"},
+  }
+
+  f, err := Marshal(&v)
+  if err != nil {
+    t.Fatalf("Marshal returned error: %v", err)
+  }
+
+  want := Form{
+    "mode":    "id_res",
+    "sig":     "abc123",
+    "tags":    "a,b",
+    "issued":  issued.Format(time.RFC3339),
+    "ns.foo":  "bar",
+  }
+  if !reflect.DeepEqual(f, want) {
+    t.Errorf("Marshal = %v, want %v", f, want)
+  }
+}
+
+func TestMarshalSigned(t *testing.T) {
+  v := marshalTestStruct{Mode: "id_res", Sig: "abc123", Tags: []string{"a"}}
+  sf, err := MarshalSigned(&v)
+  if err != nil {
+    t.Fatalf("MarshalSigned returned error: %v", err)
+  }
+  if got, want := sf.SignedFields(), "sig"; got != want {
+    t.Errorf("MarshalSigned Fields = %v, want %v", got, want)
+  }
+}
+
+func TestFormDecode(t *testing.T) {
+  issued := time.Date(2013, time.January, 2, 3, 4, 5, 0, time.UTC)
+  f := Form{
+    "mode":   "id_res",
+    "sig":    "abc123",
+    "tags":   "a,b",
+    "issued": issued.Format(time.RFC3339),
+    "ns.foo": "bar",
+  }
+
+  var v marshalTestStruct
+  if err := f.Decode(&v); err != nil {
+    t.Fatalf("Decode returned error: %v", err)
+  }
+  if v.Mode != "id_res" || v.Sig != "abc123" || v.NS.Foo != "bar" {
+    t.Errorf("Decode = %+v, want mode=id_res sig=abc123 ns.foo=bar", v)
+  }
+  if !reflect.DeepEqual(v.Tags, []string{"a", "b"}) {
+    t.Errorf("Decode Tags = %v, want [a b]", v.Tags)
+  }
+  if !v.Issued.Equal(issued) {
+    t.Errorf("Decode Issued = %v, want %v", v.Issued, issued)
+  }
+}
@@ -0,0 +1,124 @@
+// Copyright 2013 Bellua Ltd. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pgpsign wraps a keyvalue.SignedForm in an OpenPGP clear-signed
+// message, in the style used by epoint-compatible clear-signed
+// key-value documents.
+//
+// The signed octet string is SignedForm.SignedString() armored as
+//
+//   -----BEGIN PGP SIGNED MESSAGE-----
+//   Hash: <name>
+//
+//   <body, dash-escaped>
+//   -----BEGIN PGP SIGNATURE-----
+//
+//   <detached signature>
+//   -----END PGP SIGNATURE-----
+//
+// which lets a SignedForm be carried as a portable, independently
+// verifiable artifact, not just an OpenID assoc/check_authentication
+// payload.
+package pgpsign
+
+import (
+  "bytes"
+  "fmt"
+  "io"
+  "strings"
+
+  "crypto"
+
+  "golang.org/x/crypto/openpgp"
+  "golang.org/x/crypto/openpgp/clearsign"
+  "golang.org/x/crypto/openpgp/packet"
+
+  "github.com/kugutsumen/encoding/keyvalue"
+)
+
+// openidPrefix is the key prefix SignedForm.SignedString() writes for
+// every signed field.
+const openidPrefix = "openid."
+
+// ErrNotClearSigned is returned by Verify when msg does not contain an
+// OpenPGP clear-signed message.
+var ErrNotClearSigned = fmt.Errorf("pgpsign: message is not OpenPGP clear-signed")
+
+// Sign encodes sf.SignedString() as the body of an OpenPGP clear-signed
+// message and signs it with entity's private key using hash.
+func Sign(sf *keyvalue.SignedForm, entity *openpgp.Entity, hash crypto.Hash) ([]byte, error) {
+  if sf == nil {
+    return nil, keyvalue.ErrEmptyForm
+  }
+  body := sf.SignedString()
+  if len(body) == 0 {
+    return nil, keyvalue.ErrEmptyForm
+  }
+
+  var buf bytes.Buffer
+  w, err := clearsign.Encode(&buf, entity.PrivateKey, &packet.Config{DefaultHash: hash})
+  if err != nil {
+    return nil, err
+  }
+  if _, err := io.WriteString(w, body); err != nil {
+    w.Close()
+    return nil, err
+  }
+  if err := w.Close(); err != nil {
+    return nil, err
+  }
+  return buf.Bytes(), nil
+}
+
+// Verify checks the clear-signed message msg against keyring, and on
+// success decodes its body back into a SignedForm. The body produced
+// by Sign carries no separate index of which fields were signed, so
+// Fields is reconstructed from the "openid."-prefixed keys actually
+// present in the body, in the order the decoder encounters them.
+func Verify(msg []byte, keyring openpgp.KeyRing) (*keyvalue.SignedForm, *openpgp.Entity, error) {
+  block, _ := clearsign.Decode(msg)
+  if block == nil {
+    return nil, nil, ErrNotClearSigned
+  }
+
+  entity, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body)
+  if err != nil {
+    return nil, nil, err
+  }
+
+  sf, err := decodeSignedBody(block.Plaintext)
+  if err != nil {
+    return nil, nil, err
+  }
+  return sf, entity, nil
+}
+
+// decodeSignedBody parses a SignedForm.SignedString() octet string
+// back into a SignedForm, stripping the "openid." prefix each key
+// carries on the wire and recording Fields in the order the keys were
+// read.
+func decodeSignedBody(data []byte) (*keyvalue.SignedForm, error) {
+  d := keyvalue.NewDecoder(bytes.NewReader(data))
+  f := make(keyvalue.Form)
+  var fields []string
+  for {
+    k, v, err := d.Token()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return nil, err
+    }
+    if !strings.HasPrefix(k, openidPrefix) {
+      return nil, fmt.Errorf("pgpsign: field %q missing %q prefix", k, openidPrefix)
+    }
+    name := strings.TrimPrefix(k, openidPrefix)
+    f.Set(name, v)
+    fields = append(fields, name)
+  }
+  if len(fields) == 0 {
+    return nil, keyvalue.ErrEmptyForm
+  }
+  return &keyvalue.SignedForm{Form: f, Fields: fields}, nil
+}
@@ -0,0 +1,69 @@
+package pgpsign
+
+import (
+  "crypto"
+  "testing"
+
+  "golang.org/x/crypto/openpgp"
+
+  "github.com/kugutsumen/encoding/keyvalue"
+)
+
+func testEntity(t *testing.T) *openpgp.Entity {
+  entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+  if err != nil {
+    t.Fatalf("openpgp.NewEntity: %v", err)
+  }
+  return entity
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+  entity := testEntity(t)
+
+  sf := &keyvalue.SignedForm{
+    Form:   keyvalue.Form{"foo": "bar", "santa": "banta"},
+    Fields: []string{"foo", "santa"},
+  }
+
+  msg, err := Sign(sf, entity, crypto.SHA256)
+  if err != nil {
+    t.Fatalf("Sign returned error: %v", err)
+  }
+
+  keyring := openpgp.EntityList{entity}
+  got, signer, err := Verify(msg, keyring)
+  if err != nil {
+    t.Fatalf("Verify returned error: %v", err)
+  }
+  if signer.PrimaryKey.KeyId != entity.PrimaryKey.KeyId {
+    t.Errorf("Verify signer = %v, want %v", signer.PrimaryKey.KeyId, entity.PrimaryKey.KeyId)
+  }
+  if got.Form.Get("foo") != "bar" || got.Form.Get("santa") != "banta" {
+    t.Errorf("Verify decoded Form = %v, want original fields preserved", got.Form)
+  }
+  if got.SignedFields() != "foo,santa" {
+    t.Errorf("Verify decoded SignedFields() = %s, want foo,santa", got.SignedFields())
+  }
+}
+
+func TestVerifyRejectsUnknownSigner(t *testing.T) {
+  entity := testEntity(t)
+
+  sf := &keyvalue.SignedForm{
+    Form:   keyvalue.Form{"foo": "bar"},
+    Fields: []string{"foo"},
+  }
+
+  msg, err := Sign(sf, entity, crypto.SHA256)
+  if err != nil {
+    t.Fatalf("Sign returned error: %v", err)
+  }
+
+  other, err := openpgp.NewEntity("other", "", "other@example.com", nil)
+  if err != nil {
+    t.Fatalf("openpgp.NewEntity: %v", err)
+  }
+  if _, _, err := Verify(msg, openpgp.EntityList{other}); err == nil {
+    t.Errorf("Verify with wrong keyring: want error, got nil")
+  }
+}
@@ -0,0 +1,104 @@
+// Copyright 2013 Bellua Ltd. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keyvalue
+
+import (
+  "crypto/hmac"
+  "crypto/rand"
+  "crypto/sha1"
+  "crypto/sha256"
+  "encoding/base64"
+  "fmt"
+  "hash"
+)
+
+// ErrUnsupportedAssocType is returned when the requested association
+// type is neither "HMAC-SHA1" nor "HMAC-SHA256", as defined by OpenID
+// Authentication 2.0 section 8.3.
+var ErrUnsupportedAssocType = fmt.Errorf("keyvalue: unsupported association type")
+
+// macHash returns the hash constructor for assocType, as specified by
+// OpenID Authentication 2.0 section 6.
+func macHash(assocType string) (func() hash.Hash, error) {
+  switch assocType {
+  case "HMAC-SHA1":
+    return sha1.New, nil
+  case "HMAC-SHA256":
+    return sha256.New, nil
+  }
+  return nil, ErrUnsupportedAssocType
+}
+
+// Sign computes the HMAC over SignedString() using macKey and
+// assocType ("HMAC-SHA1" or "HMAC-SHA256"), base64-encodes it, and
+// stores the result along with the list of signed fields in the
+// underlying Form as "sig" and "signed", alongside the rest of
+// Fields's bare (un-prefixed) keys.
+func (s *SignedForm) Sign(assocType string, macKey []byte) (sig string, err error) {
+  newHash, err := macHash(assocType)
+  if err != nil {
+    return "", err
+  }
+
+  octets := s.SignedString()
+  if len(octets) == 0 {
+    return "", ErrEmptyForm
+  }
+
+  mac := hmac.New(newHash, macKey)
+  mac.Write([]byte(octets))
+  sig = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+  s.Form.Set("sig", sig)
+  s.Form.Set("signed", s.SignedFields())
+  return sig, nil
+}
+
+// Verify recomputes the HMAC over the octet string derived from the
+// current Fields order and compares it against sig in constant time.
+// Fields MUST match the "signed" value of the message being verified.
+func (s *SignedForm) Verify(assocType string, macKey []byte, sig string) error {
+  newHash, err := macHash(assocType)
+  if err != nil {
+    return err
+  }
+
+  if got, want := s.SignedFields(), s.Form.Get("signed"); got != want {
+    return fmt.Errorf("keyvalue: Fields %q does not match signed %q", got, want)
+  }
+
+  octets := s.SignedString()
+  if len(octets) == 0 {
+    return ErrEmptyForm
+  }
+
+  want, err := base64.StdEncoding.DecodeString(sig)
+  if err != nil {
+    return fmt.Errorf("keyvalue: malformed signature: %v", err)
+  }
+
+  mac := hmac.New(newHash, macKey)
+  mac.Write([]byte(octets))
+  if !hmac.Equal(mac.Sum(nil), want) {
+    return fmt.Errorf("keyvalue: signature mismatch")
+  }
+  return nil
+}
+
+// GenerateMACKey returns a random key sized for assocType's hash
+// function ("HMAC-SHA1" or "HMAC-SHA256"), suitable for use with Sign
+// and Verify.
+func GenerateMACKey(assocType string) ([]byte, error) {
+  newHash, err := macHash(assocType)
+  if err != nil {
+    return nil, err
+  }
+
+  key := make([]byte, newHash().Size())
+  if _, err := rand.Read(key); err != nil {
+    return nil, err
+  }
+  return key, nil
+}